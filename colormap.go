@@ -2,7 +2,10 @@ package main
 
 import (
 	"io"
+	"os"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/fatih/color"
 )
@@ -43,13 +46,87 @@ var colorMap = map[string]color.Attribute{
 	"REVERSEVIDEO": color.ReverseVideo,
 }
 
-func displayColorList(w io.Writer) {
+// parseColorCodes converts the trailing elements of a dashvar into color
+// attributes. Named attributes come from colorMap; FG or BG followed by an
+// r_g_b triple (FG255_128_0) or HEXrrggbb (FGHEXff8000) select a 24-bit
+// color. Invalid codes are ignored.
+func parseColorCodes(elements []string) []color.Attribute {
+	attrs := make([]color.Attribute, 0)
+	for i := 0; i < len(elements); i++ {
+		code := elements[i]
+		if attr, ok := colorMap[code]; ok {
+			attrs = append(attrs, attr)
+			continue
+		}
+		var base color.Attribute
+		switch {
+		case strings.HasPrefix(code, "FG"):
+			base = 38
+		case strings.HasPrefix(code, "BG"):
+			base = 48
+		default:
+			continue
+		}
+		var rgb []color.Attribute
+		if hex := strings.TrimPrefix(code[2:], "HEX"); hex != code[2:] {
+			rgb = parseHexRGB(hex)
+		} else if i+2 < len(elements) {
+			rgb = parseRGBTriple(code[2:], elements[i+1], elements[i+2])
+			if rgb != nil {
+				i += 2
+			}
+		}
+		if rgb != nil {
+			attrs = append(attrs, base, 2)
+			attrs = append(attrs, rgb...)
+		}
+	}
+	return attrs
+}
+
+// parseRGBTriple parses three decimal components in the range 0-255,
+// returning nil if any is invalid.
+func parseRGBTriple(r, g, b string) []color.Attribute {
+	rgb := make([]color.Attribute, 0, 3)
+	for _, component := range []string{r, g, b} {
+		v, err := strconv.ParseUint(component, 10, 8)
+		if err != nil {
+			return nil
+		}
+		rgb = append(rgb, color.Attribute(v))
+	}
+	return rgb
+}
+
+// parseHexRGB parses a six digit rrggbb hex string, returning nil if it is
+// invalid.
+func parseHexRGB(hex string) []color.Attribute {
+	if len(hex) != 6 {
+		return nil
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil
+	}
+	return []color.Attribute{
+		color.Attribute(v >> 16 & 0xff),
+		color.Attribute(v >> 8 & 0xff),
+		color.Attribute(v & 0xff),
+	}
+}
+
+func sortedColorNames() []string {
 	keys := make([]string, 0)
 	for k := range colorMap {
 		keys = append(keys, k)
 	}
-	sizeKeys := len(keys)
 	sort.Strings(keys)
+	return keys
+}
+
+func displayColorList(w io.Writer) {
+	keys := sortedColorNames()
+	sizeKeys := len(keys)
 	flexPrintln(w, "Supported color attributes:")
 	for i, attrib := range keys {
 		flexPrintf(w, "%s", attrib)
@@ -58,4 +135,46 @@ func displayColorList(w io.Writer) {
 		}
 	}
 	flexPrintln(w, "")
+	flexPrintln(w, "24-bit colors: FG<r>_<g>_<b> or BG<r>_<g>_<b> (0-255) and FGHEX<rrggbb> or BGHEX<rrggbb>.")
+}
+
+// colorMode resolves whether output is colorized: "always", "never", or
+// "auto" to leave it to TTY detection. Precedence is --color, then
+// --no-color, then DASHLIGHTS_FORCE_COLOR (for prompt frameworks that capture
+// output but render it in a terminal), then NO_COLOR (https://no-color.org).
+func colorMode() string {
+	switch args.Color {
+	case "always", "never":
+		return args.Color
+	}
+	if args.NoColor {
+		return "never"
+	}
+	if os.Getenv("DASHLIGHTS_FORCE_COLOR") != "" {
+		return "always"
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return "never"
+	}
+	return "auto"
+}
+
+func colorDisabled() bool {
+	return colorMode() == "never"
+}
+
+// displayColorTest renders each supported attribute name in its own color,
+// falling back to plain names when color is disabled.
+func displayColorTest(w io.Writer) {
+	noColor := colorDisabled()
+	flexPrintln(w, "Supported color attributes:")
+	for _, attrib := range sortedColorNames() {
+		c := color.New(colorMap[attrib])
+		if noColor {
+			c.DisableColor()
+		} else {
+			c.EnableColor()
+		}
+		flexPrintln(w, c.Sprint(attrib))
+	}
 }
@@ -2,8 +2,11 @@ package main
 
 import (
 	"bytes"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/fatih/color"
 )
 
 func TestDisplayColorList(t *testing.T) {
@@ -18,4 +21,108 @@ func TestDisplayColorList(t *testing.T) {
 	if !strings.Contains(b.String(), "BGWHITE") {
 		t.Error("Expected to see string 'BGWHITE' in: ", b.String())
 	}
+	// 24-bit color syntax is documented...
+	if !strings.Contains(b.String(), "FGHEX<rrggbb>") {
+		t.Error("Expected to see 24-bit color syntax in: ", b.String())
+	}
+}
+
+func TestDisplayColorTest(t *testing.T) {
+	t.Setenv("DASHLIGHTS_FORCE_COLOR", "")
+	t.Setenv("NO_COLOR", "")
+	var b bytes.Buffer
+	displayColorTest(&b)
+	for attrib := range colorMap {
+		if !strings.Contains(b.String(), attrib) {
+			t.Errorf("Expected to see '%s' in: %s", attrib, b.String())
+		}
+	}
+	if !strings.Contains(b.String(), "\x1b[") {
+		t.Error("Expected color escape codes in: ", b.String())
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	b.Reset()
+	displayColorTest(&b)
+	if !strings.Contains(b.String(), "FGRED") {
+		t.Error("Expected to see string 'FGRED' in: ", b.String())
+	}
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Error("Expected no color escape codes with NO_COLOR set, got: ", b.String())
+	}
+}
+
+func TestColorDisabled(t *testing.T) {
+	t.Setenv("DASHLIGHTS_FORCE_COLOR", "")
+	t.Setenv("NO_COLOR", "")
+	if colorDisabled() {
+		t.Error("Expected color to be enabled by default")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if !colorDisabled() {
+		t.Error("Expected NO_COLOR to disable color")
+	}
+	t.Setenv("NO_COLOR", "")
+	args.NoColor = true
+	defer func() { args.NoColor = false }()
+	if !colorDisabled() {
+		t.Error("Expected --no-color to disable color")
+	}
+}
+
+func TestColorMode(t *testing.T) {
+	defer func() {
+		args.Color = ""
+		args.NoColor = false
+	}()
+	tests := []struct {
+		color      string
+		noColor    bool
+		forceColor string
+		noColorEnv string
+		expected   string
+	}{
+		{"", false, "", "", "auto"},
+		{"", false, "", "1", "never"},
+		{"", false, "1", "1", "always"},
+		{"", true, "1", "1", "never"},
+		{"always", true, "", "1", "always"},
+		{"never", false, "1", "", "never"},
+		{"auto", false, "", "1", "never"},
+		{"auto", false, "", "", "auto"},
+	}
+	for _, tt := range tests {
+		args.Color = tt.color
+		args.NoColor = tt.noColor
+		t.Setenv("DASHLIGHTS_FORCE_COLOR", tt.forceColor)
+		t.Setenv("NO_COLOR", tt.noColorEnv)
+		if got := colorMode(); got != tt.expected {
+			t.Errorf("Expected color mode '%s' for %+v, got '%s'", tt.expected, tt, got)
+		}
+	}
+}
+
+func TestParseColorCodes(t *testing.T) {
+	tests := []struct {
+		elements []string
+		expected []color.Attribute
+	}{
+		{[]string{"FGRED", "BGWHITE"}, []color.Attribute{color.FgRed, color.BgWhite}},
+		{[]string{"FG255", "128", "0"}, []color.Attribute{38, 2, 255, 128, 0}},
+		{[]string{"BG0", "0", "255", "FGRED"}, []color.Attribute{48, 2, 0, 0, 255, color.FgRed}},
+		{[]string{"FGHEXff8000"}, []color.Attribute{38, 2, 255, 128, 0}},
+		{[]string{"BGHEX0080FF"}, []color.Attribute{48, 2, 0, 128, 255}},
+		// invalid codes are ignored...
+		{[]string{"NOTACODE"}, []color.Attribute{}},
+		{[]string{"FG256", "0", "0"}, []color.Attribute{}},
+		{[]string{"FG255", "128"}, []color.Attribute{}},
+		{[]string{"FGHEXff80"}, []color.Attribute{}},
+		{[]string{"BGHEXgg8000", "FGBLUE"}, []color.Attribute{color.FgBlue}},
+	}
+	for _, tt := range tests {
+		got := parseColorCodes(tt.elements)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Expected %v for %v, got %v", tt.expected, tt.elements, got)
+		}
+	}
 }
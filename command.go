@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// cmdLightsTimeout bounds the total time spent running DASHLIGHTS_CMD_
+// commands, so a hanging command can't block the prompt.
+var cmdLightsTimeout = 200 * time.Millisecond
+
+// cmdLightsAllowed reports whether DASHLIGHTS_CMD_{name} commands may run.
+// Running commands from the environment is opt-in.
+func cmdLightsAllowed() bool {
+	return os.Getenv("DASHLIGHTS_ALLOW_CMD_LIGHTS") == "1"
+}
+
+// runDiagnosticCmds replaces the diagnostic of each light that has a
+// DiagnosticCmd with the first line of the command's output. Lights whose
+// command fails or runs out of time keep their static diagnostic.
+func runDiagnosticCmds(ctx context.Context, lights *[]dashlight) {
+	for i := range *lights {
+		light := &(*lights)[i]
+		if light.DiagnosticCmd == "" {
+			continue
+		}
+		if diagnostic, err := runDiagnosticCmd(ctx, light.DiagnosticCmd); err == nil && diagnostic != "" {
+			light.Diagnostic = diagnostic
+		}
+	}
+}
+
+func runDiagnosticCmd(ctx context.Context, command string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	// don't wait on grandchildren still holding stdout once ctx is done
+	cmd.WaitDelay = 10 * time.Millisecond
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	line, _ := bufio.NewReader(bytes.NewReader(out)).ReadString('\n')
+	return strings.TrimSpace(line), nil
+}
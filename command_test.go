@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmdLightsAllowed(t *testing.T) {
+	t.Setenv("DASHLIGHTS_ALLOW_CMD_LIGHTS", "")
+	if cmdLightsAllowed() {
+		t.Error("Command lights should not be allowed by default!")
+	}
+	t.Setenv("DASHLIGHTS_ALLOW_CMD_LIGHTS", "1")
+	if !cmdLightsAllowed() {
+		t.Error("Expected DASHLIGHTS_ALLOW_CMD_LIGHTS=1 to allow command lights")
+	}
+}
+
+func TestRunDiagnosticCmds(t *testing.T) {
+	environ := []string{
+		"DASHLIGHT_FAST_0021=static",
+		"DASHLIGHTS_CMD_FAST=printf '  first line  \\nsecond line\\n'",
+		"DASHLIGHT_FAILS_0022=static",
+		"DASHLIGHTS_CMD_FAILS=exit 1",
+		"DASHLIGHT_PLAIN_0023=static",
+	}
+	lights := make([]dashlight, 0)
+	parseEnviron(environ, &lights)
+
+	runDiagnosticCmds(context.Background(), &lights)
+	expected := []string{"first line", "static", "static"}
+	for i, light := range lights {
+		if light.Diagnostic != expected[i] {
+			t.Errorf("Expected %s diagnostic '%s', got '%s'", light.Name, expected[i], light.Diagnostic)
+		}
+	}
+}
+
+func TestRunDiagnosticCmdsTimeout(t *testing.T) {
+	lights := make([]dashlight, 0)
+	parseEnviron([]string{
+		"DASHLIGHT_SLOW_0021=static",
+		"DASHLIGHTS_CMD_SLOW=sleep 5; echo done",
+	}, &lights)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	runDiagnosticCmds(ctx, &lights)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected slow command to be cancelled, took %s", elapsed)
+	}
+	if lights[0].Diagnostic != "static" {
+		t.Error("Expected static diagnostic to be kept, got ", lights[0].Diagnostic)
+	}
+}
+
+func TestCmdLightsDisplay(t *testing.T) {
+	environ := []string{
+		"DASHLIGHT_DYN_0021=static",
+		"DASHLIGHTS_CMD_DYN=echo dynamic diagnostic",
+	}
+	t.Setenv("DASHLIGHTS_ALLOW_CMD_LIGHTS", "")
+	args.ObdMode = true
+	defer func() { args.ObdMode = false }()
+
+	lights := make([]dashlight, 0)
+	parseEnviron(environ, &lights)
+	var b bytes.Buffer
+	display(&b, &lights, nil)
+	if !strings.Contains(b.String(), " DYN - static") {
+		t.Errorf("Expected command not to run without opt-in, found:\n%s", b.String())
+	}
+
+	t.Setenv("DASHLIGHTS_ALLOW_CMD_LIGHTS", "1")
+	lights = make([]dashlight, 0)
+	parseEnviron(environ, &lights)
+	b.Reset()
+	display(&b, &lights, nil)
+	if !strings.Contains(b.String(), " DYN - dynamic diagnostic") {
+		t.Errorf("Expected command output as diagnostic, found:\n%s", b.String())
+	}
+}
+
+func TestCmdLightsOnlyRunWhenShown(t *testing.T) {
+	t.Setenv("DASHLIGHTS_ALLOW_CMD_LIGHTS", "1")
+	defer func() {
+		args.ObdMode = false
+		args.PromptMode = false
+		args.Output = ""
+	}()
+	marker := filepath.Join(t.TempDir(), "ran")
+	environ := []string{
+		"DASHLIGHT_RUN_0021=static",
+		"DASHLIGHTS_CMD_RUN=touch " + marker + "; echo dynamic",
+	}
+
+	tests := []struct {
+		obd    bool
+		prompt bool
+		output string
+		runs   bool
+	}{
+		{false, false, "", false},
+		{true, false, "", true},
+		{true, true, "", false},
+		{false, false, "tsv", true},
+		{false, false, "json", true},
+		{false, false, "sarif", true},
+		{true, false, "prometheus", false},
+	}
+	for _, tt := range tests {
+		os.Remove(marker)
+		args.ObdMode, args.PromptMode, args.Output = tt.obd, tt.prompt, tt.output
+		lights := make([]dashlight, 0)
+		parseEnviron(environ, &lights)
+		var b bytes.Buffer
+		display(&b, &lights, nil)
+		_, err := os.Stat(marker)
+		if ran := err == nil; ran != tt.runs {
+			t.Errorf("Expected command run to be %v for %+v, got %v", tt.runs, tt, ran)
+		}
+		if tt.output == "tsv" && !strings.Contains(b.String(), "\tdynamic\t") {
+			t.Errorf("Expected command output in tsv without --obd, got: %q", b.String())
+		}
+	}
+}
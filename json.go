@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonSchemaVersion is bumped whenever the JSON output changes incompatibly.
+const jsonSchemaVersion = 1
+
+type jsonLight struct {
+	Name       string `json:"name"`
+	Glyph      string `json:"glyph"`
+	Diagnostic string `json:"diagnostic"`
+	Unset      string `json:"unset"`
+}
+
+type jsonOutput struct {
+	Schema int         `json:"schema"`
+	Lights []jsonLight `json:"lights"`
+	Count  int         `json:"count"`
+}
+
+func displayJSON(w io.Writer, lights *[]dashlight) {
+	out := jsonOutput{
+		Schema: jsonSchemaVersion,
+		Lights: make([]jsonLight, 0, len(*lights)),
+		Count:  len(*lights),
+	}
+	for _, light := range *lights {
+		out.Lights = append(out.Lights, jsonLight{
+			Name:       light.Name,
+			Glyph:      light.Glyph,
+			Diagnostic: light.Diagnostic,
+			Unset:      light.UnsetString,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(out)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestDisplayJSON(t *testing.T) {
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_FOO_0021_FGRED=foo diagnostic")
+	parseDashlightFromEnv(&lights, "DASHLIGHT_BAR_0022=")
+	displayJSON(&b, &lights)
+
+	var out jsonOutput
+	if err := json.Unmarshal(b.Bytes(), &out); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v in:\n%s", err, b.String())
+	}
+	if out.Schema != jsonSchemaVersion {
+		t.Errorf("Expected schema %d, got %d", jsonSchemaVersion, out.Schema)
+	}
+	if out.Count != 2 || len(out.Lights) != 2 {
+		t.Fatalf("Expected 2 lights, got count %d and %d entries", out.Count, len(out.Lights))
+	}
+	expected := jsonLight{
+		Name:       "FOO",
+		Glyph:      "!",
+		Diagnostic: "foo diagnostic",
+		Unset:      "unset DASHLIGHT_FOO_0021_FGRED",
+	}
+	if out.Lights[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, out.Lights[0])
+	}
+	if out.Lights[1].Name != "BAR" {
+		t.Error("Expected second light to be 'BAR', got ", out.Lights[1].Name)
+	}
+}
+
+func TestDisplayJSONEmpty(t *testing.T) {
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	displayJSON(&b, &lights)
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &raw); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v in:\n%s", err, b.String())
+	}
+	if _, ok := raw["lights"].([]interface{}); !ok {
+		t.Errorf("Expected 'lights' to be an empty array, got: %s", b.String())
+	}
+}
@@ -1,28 +1,43 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	arg "github.com/alexflint/go-arg"
 	"github.com/fatih/color"
 )
 
 type dashlight struct {
-	Name        string
-	Glyph       string
-	Diagnostic  string
-	Color       *color.Color
-	UnsetString string
+	Name          string
+	Glyph         string
+	Diagnostic    string
+	Color         *color.Color
+	UnsetString   string
+	Priority      int
+	ExpiresAt     *time.Time
+	DiagnosticCmd string
 }
 
 var args struct {
-	ObdMode   bool `arg:"-d,--obd,help:On-Board Diagnostics: display diagnostic info if provided."`
-	ListMode  bool `arg:"-l,--list,help:List supported color attributes."`
-	ClearMode bool `arg:"-c,--clear,help:Shell code to clear set dashlights."`
+	ObdMode       bool   `arg:"-d,--obd,help:On-Board Diagnostics: display diagnostic info if provided."`
+	ListMode      bool   `arg:"-l,--list,help:List supported color attributes."`
+	ClearMode     bool   `arg:"-c,--clear,help:Shell code to clear set dashlights."`
+	ColorTestMode bool   `arg:"-t,--color-test,help:Render each supported color attribute."`
+	PromptMode    bool   `arg:"-p,--prompt,help:Shell-escaped output for embedding in a prompt."`
+	Shell         string `arg:"-s,--shell,help:Shell to escape prompt output for (default: $SHELL)."`
+	NoNewline     bool   `arg:"-n,--no-newline,help:Omit the trailing newline (also honors DASHLIGHTS_NO_NEWLINE)."`
+	Color         string `arg:"--color,help:Colorize output: always|never|auto (default: auto)."`
+	NoColor       bool   `arg:"--no-color,help:Disable color output (also honors NO_COLOR)."`
+	Output        string `arg:"-o,--output,help:Output format: text|json|tsv|sarif|prometheus (default: text)."`
+	JSONMode      bool   `arg:"-j,--json,help:Deprecated: use --output json."`
+	LightsSort    string `arg:"--lights-sort,help:Order dashlights by name|glyph|insertion (default: insertion)."`
 }
 
 func flexPrintf(w io.Writer, format string, args ...interface{}) {
@@ -39,36 +54,169 @@ func displayClearCodes(w io.Writer, lights *[]dashlight) {
 	}
 }
 
+// displayClearSettings prints shell code to unset DASHLIGHTS_{setting}_{name}
+// vars, including any left behind by lights that were already cleared.
+func displayClearSettings(w io.Writer, settingVars []string) {
+	for _, settingVar := range settingVars {
+		flexPrintln(w, "unset "+settingVar)
+	}
+}
+
 var lights []dashlight
+var settingVars []string
 
 func init() {
-	parseEnviron(os.Environ(), &lights)
+	settingVars = parseEnviron(os.Environ(), &lights)
 }
 
 func main() {
-	arg.MustParse(&args)
-	display(os.Stdout, &lights)
+	p := arg.MustParse(&args)
+	if err := validateArgs(); err != nil {
+		p.Fail(err.Error())
+	}
+	display(os.Stdout, &lights, settingVars)
+}
+
+// validateArgs rejects values outside the fixed set a flag accepts.
+func validateArgs() error {
+	if !oneOf(args.LightsSort, "", "name", "glyph", "insertion") {
+		return fmt.Errorf("--lights-sort must be one of name|glyph|insertion, got '%s'", args.LightsSort)
+	}
+	if !oneOf(args.Output, "", "text", "json", "tsv", "sarif", "prometheus") {
+		return fmt.Errorf("--output must be one of text|json|tsv|sarif|prometheus, got '%s'", args.Output)
+	}
+	if !oneOf(args.Color, "", "always", "never", "auto") {
+		return fmt.Errorf("--color must be one of always|never|auto, got '%s'", args.Color)
+	}
+	return nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// settingNames are the settings a DASHLIGHTS_{setting}_{name} var can carry
+// for the light with that name. The DASHLIGHTS_ prefix keeps them apart from
+// DASHLIGHT_ vars, so any light name remains valid.
+var settingNames = map[string]bool{
+	"PRIO": true,
+	"TTL":  true,
+	"CMD":  true,
 }
 
-func parseEnviron(environ []string, lights *[]dashlight) {
+// parseEnviron parses dashlights from environ, applies any per-light settings
+// to them, and returns the names of the setting vars it consumed.
+func parseEnviron(environ []string, lights *[]dashlight) []string {
+	settingVars := make([]string, 0)
+	settings := make(map[string]map[string]string)
+	for setting := range settingNames {
+		settings[setting] = make(map[string]string)
+	}
 	for _, env := range environ {
+		if setting, name, value, ok := parseSettingFromEnv(env); ok {
+			settings[setting][name] = value
+			settingVars = append(settingVars, "DASHLIGHTS_"+setting+"_"+name)
+			continue
+		}
 		parseDashlightFromEnv(lights, env)
 	}
+	for i := range *lights {
+		light := &(*lights)[i]
+		if priority, err := strconv.Atoi(settings["PRIO"][light.Name]); err == nil {
+			light.Priority = priority
+		}
+		if ttl, err := strconv.ParseInt(settings["TTL"][light.Name], 10, 64); err == nil {
+			expiresAt := time.Unix(ttl, 0)
+			light.ExpiresAt = &expiresAt
+		}
+		light.DiagnosticCmd = settings["CMD"][light.Name]
+	}
+	return settingVars
 }
 
-func display(w io.Writer, lights *[]dashlight) {
+// parseSettingFromEnv parses a DASHLIGHTS_{setting}_{name}={value} var, where
+// setting is one of settingNames: PRIO sets the display priority of the
+// named light, TTL the unix timestamp after which it is no longer displayed,
+// and CMD a shell command whose output replaces its diagnostic.
+func parseSettingFromEnv(env string) (string, string, string, bool) {
+	kv := strings.SplitN(env, "=", 2)
+	elements := strings.SplitN(kv[0], "_", 3)
+	if len(kv) != 2 || len(elements) != 3 || elements[0] != "DASHLIGHTS" || !settingNames[elements[1]] {
+		return "", "", "", false
+	}
+	return elements[1], elements[2], kv[1], true
+}
+
+// activeLights returns the lights that have not expired as of now.
+func activeLights(lights []dashlight, now time.Time) []dashlight {
+	active := make([]dashlight, 0, len(lights))
+	for _, light := range lights {
+		if light.ExpiresAt == nil || now.Before(*light.ExpiresAt) {
+			active = append(active, light)
+		}
+	}
+	return active
+}
+
+func display(w io.Writer, lights *[]dashlight, settingVars []string) {
+	switch colorMode() {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	}
+	sortLights(lights, args.LightsSort)
 	if args.ListMode {
 		displayColorList(w)
 		return
 	}
+	if args.ColorTestMode {
+		displayColorTest(w)
+		return
+	}
 	if args.ClearMode {
 		displayClearCodes(w, lights)
+		displayClearSettings(w, settingVars)
 		return
 	}
-	displayDashlights(w, lights)
-	if args.ObdMode {
-		displayDiagnostics(w, lights)
+	active := activeLights(*lights, time.Now())
+	lights = &active
+	if args.PromptMode {
+		displayPrompt(w, lights, promptShell())
+		return
+	}
+	format := outputFormat()
+	if showsDiagnostics(format) && cmdLightsAllowed() {
+		ctx, cancel := context.WithTimeout(context.Background(), cmdLightsTimeout)
+		runDiagnosticCmds(ctx, lights)
+		cancel()
 	}
+	displayFormat(w, lights, format)
+}
+
+// sortLights orders lights by priority (lower first), then by the given
+// mode: "name", "glyph", or "insertion". Insertion, the default, keeps
+// lights of equal priority in the order they were parsed.
+func sortLights(lights *[]dashlight, mode string) {
+	less := func(a, b dashlight) bool { return false }
+	switch mode {
+	case "name":
+		less = func(a, b dashlight) bool { return a.Name < b.Name }
+	case "glyph":
+		less = func(a, b dashlight) bool { return a.Glyph < b.Glyph }
+	}
+	l := *lights
+	sort.SliceStable(l, func(i, j int) bool {
+		if l[i].Priority != l[j].Priority {
+			return l[i].Priority < l[j].Priority
+		}
+		return less(l[i], l[j])
+	})
 }
 
 func displayDashlights(w io.Writer, lights *[]dashlight) {
@@ -76,11 +224,17 @@ func displayDashlights(w io.Writer, lights *[]dashlight) {
 		lamp := light.Color.SprintfFunc()("%s ", light.Glyph)
 		flexPrintf(w, "%s ", lamp)
 	}
-	if len(*lights) > 0 {
+	if len(*lights) > 0 && !noNewline() {
 		flexPrintln(w, "")
 	}
 }
 
+// noNewline reports whether the trailing newline should be omitted, for
+// embedding output inline in a prompt built by another tool.
+func noNewline() bool {
+	return args.NoNewline || os.Getenv("DASHLIGHTS_NO_NEWLINE") != ""
+}
+
 func displayDiagnostics(w io.Writer, lights *[]dashlight) {
 	flexPrintf(w, "\n-------- Diagnostics --------\n")
 	for _, light := range *lights {
@@ -109,11 +263,8 @@ func parseDashlightFromEnv(lights *[]dashlight, env string) {
 		if err != nil {
 			return
 		}
-		dashColor := color.New()
 		// process any remaining elements as color additions
-		for _, colorstr := range elements {
-			dashColor.Add(colorMap[colorstr])
-		}
+		dashColor := color.New(parseColorCodes(elements)...)
 		*lights = append(*lights, dashlight{
 			Name:        name,
 			Glyph:       glyph,
@@ -129,5 +280,5 @@ func utf8HexToString(hex string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return string(i), nil
+	return string(rune(i)), nil
 }
@@ -3,8 +3,10 @@ package main
 import (
 	"bytes"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fatih/color"
 )
@@ -90,6 +92,23 @@ func TestParseDashlightFromEnv(t *testing.T) {
 	}
 }
 
+func TestParseDashlightTrueColor(t *testing.T) {
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_ROCKET_1F680_FG255_128_0=")
+	parseDashlightFromEnv(&lights, "DASHLIGHT_DEPLOY_1F680_BGHEXFF8000=")
+	if 2 != len(lights) {
+		t.Fatal("Expected length of 2, got ", len(lights))
+	}
+	expected := []string{"\x1b[38;2;255;128;0m", "\x1b[48;2;255;128;0m"}
+	for i, light := range lights {
+		light.Color.EnableColor()
+		lamp := light.Color.Sprint(light.Glyph)
+		if !strings.HasPrefix(lamp, expected[i]) {
+			t.Errorf("Expected %s lamp to start with %q, got %q", light.Name, expected[i], lamp)
+		}
+	}
+}
+
 func TestDisplayDiagnostics(t *testing.T) {
 	var b bytes.Buffer
 	lights := make([]dashlight, 0)
@@ -128,6 +147,21 @@ func TestDefaultFlagStates(t *testing.T) {
 	if args.ClearMode {
 		t.Error("Clear mode should not start enabled!")
 	}
+	if args.ColorTestMode {
+		t.Error("Color test mode should not start enabled!")
+	}
+	if args.PromptMode {
+		t.Error("Prompt mode should not start enabled!")
+	}
+	if args.JSONMode {
+		t.Error("JSON mode should not start enabled!")
+	}
+	if args.NoColor {
+		t.Error("No color mode should not start enabled!")
+	}
+	if args.NoNewline {
+		t.Error("No newline mode should not start enabled!")
+	}
 }
 
 func TestListColorModeDisplay(t *testing.T) {
@@ -138,12 +172,97 @@ func TestListColorModeDisplay(t *testing.T) {
 	lights := make([]dashlight, 0)
 	parseDashlightFromEnv(&lights, "DASHLIGHT_LCM_0021=")
 
-	display(&b, &lights)
+	display(&b, &lights, nil)
 	if !strings.Contains(b.String(), "BGWHITE") {
 		t.Errorf("List mode should contain color attribute keys, found: %s", b.String())
 	}
 }
 
+func TestColorTestModeDisplay(t *testing.T) {
+	args.ColorTestMode = true
+	defer func() { args.ColorTestMode = false }()
+
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_CTM_0021=")
+
+	display(&b, &lights, nil)
+	if !strings.Contains(b.String(), "FGHIMAGENTA") {
+		t.Errorf("Color test mode should contain color attribute keys, found: %s", b.String())
+	}
+	if strings.Contains(b.String(), "!") {
+		t.Errorf("Color test mode should not display dashlights, found: %s", b.String())
+	}
+}
+
+func TestJSONModeDisplay(t *testing.T) {
+	args.JSONMode = true
+	defer func() { args.JSONMode = false }()
+
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_JM_0021=")
+
+	display(&b, &lights, nil)
+	expectStr := `"name": "JM"`
+	if !strings.Contains(b.String(), expectStr) {
+		t.Errorf("JSON mode should contain '%s', found: %s", expectStr, b.String())
+	}
+}
+
+func TestNoColorDisplay(t *testing.T) {
+	oldNoColor := color.NoColor
+	defer func() { color.NoColor = oldNoColor }()
+	args.ObdMode = true
+	defer func() { args.ObdMode = false }()
+
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_NC_0021_FGRED=no color diagnostic")
+
+	t.Setenv("DASHLIGHTS_FORCE_COLOR", "")
+	t.Setenv("NO_COLOR", "1")
+	color.NoColor = false
+	var b bytes.Buffer
+	display(&b, &lights, nil)
+	t.Setenv("NO_COLOR", "")
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Errorf("Expected no escape sequences with NO_COLOR set, got: %q", b.String())
+	}
+
+	args.NoColor = true
+	defer func() { args.NoColor = false }()
+	color.NoColor = false
+	b.Reset()
+	display(&b, &lights, nil)
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Errorf("Expected no escape sequences with --no-color, got: %q", b.String())
+	}
+}
+
+func TestForceColorDisplay(t *testing.T) {
+	oldNoColor := color.NoColor
+	defer func() { color.NoColor = oldNoColor }()
+
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_FC_0021_FGRED=")
+
+	t.Setenv("DASHLIGHTS_FORCE_COLOR", "1")
+	color.NoColor = true
+	var b bytes.Buffer
+	display(&b, &lights, nil)
+	if !strings.Contains(b.String(), "\x1b[31m") {
+		t.Errorf("Expected escape sequences with DASHLIGHTS_FORCE_COLOR set, got: %q", b.String())
+	}
+
+	args.Color = "never"
+	defer func() { args.Color = "" }()
+	b.Reset()
+	display(&b, &lights, nil)
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Errorf("Expected --color never to win over DASHLIGHTS_FORCE_COLOR, got: %q", b.String())
+	}
+}
+
 func TestClearModeDisplay(t *testing.T) {
 	args.ClearMode = true
 	defer func() { args.ClearMode = false }()
@@ -152,7 +271,7 @@ func TestClearModeDisplay(t *testing.T) {
 	lights := make([]dashlight, 0)
 	parseDashlightFromEnv(&lights, "DASHLIGHT_CM_0021=")
 
-	display(&b, &lights)
+	display(&b, &lights, nil)
 	expectStr := "unset DASHLIGHT_CM_0021"
 	if !strings.Contains(b.String(), expectStr) {
 		t.Errorf("Clear mode should '%s', found: %s", expectStr, b.String())
@@ -167,7 +286,7 @@ func TestDiagModeDisplay(t *testing.T) {
 	lights := make([]dashlight, 0)
 	parseDashlightFromEnv(&lights, "DASHLIGHT_DM_0021=bar diagnostic")
 
-	display(&b, &lights)
+	display(&b, &lights, nil)
 	if !strings.Contains(b.String(), lights[0].Glyph) {
 		t.Errorf("Diag mode should lead with dashlight display containing '%s', found: '%s'", lights[0].Glyph, b.String())
 	}
@@ -190,3 +309,271 @@ func TestParseEnviron(t *testing.T) {
 		t.Error("Failed to parse from environ key=val strings.")
 	}
 }
+
+func TestValidateArgs(t *testing.T) {
+	defer func() {
+		args.LightsSort = ""
+		args.Output = ""
+		args.Color = ""
+	}()
+	for _, mode := range []string{"", "name", "glyph", "insertion"} {
+		args.LightsSort = mode
+		if err := validateArgs(); err != nil {
+			t.Errorf("Expected --lights-sort '%s' to be valid, got: %v", mode, err)
+		}
+	}
+	args.LightsSort = "bogus"
+	if err := validateArgs(); err == nil || !strings.Contains(err.Error(), "--lights-sort") {
+		t.Error("Expected an error naming --lights-sort for an unknown mode, got: ", err)
+	}
+	args.LightsSort = ""
+
+	for _, format := range []string{"", "text", "json", "tsv", "sarif", "prometheus"} {
+		args.Output = format
+		if err := validateArgs(); err != nil {
+			t.Errorf("Expected --output '%s' to be valid, got: %v", format, err)
+		}
+	}
+	args.Output = "jsno"
+	if err := validateArgs(); err == nil || !strings.Contains(err.Error(), "--output") {
+		t.Error("Expected an error naming --output for an unknown format, got: ", err)
+	}
+	args.Output = ""
+
+	for _, mode := range []string{"", "always", "never", "auto"} {
+		args.Color = mode
+		if err := validateArgs(); err != nil {
+			t.Errorf("Expected --color '%s' to be valid, got: %v", mode, err)
+		}
+	}
+	args.Color = "yes"
+	if err := validateArgs(); err == nil || !strings.Contains(err.Error(), "--color") {
+		t.Error("Expected an error naming --color for an unknown mode, got: ", err)
+	}
+}
+
+func TestSortLights(t *testing.T) {
+	environ := []string{
+		"DASHLIGHT_CHARLIE_0041=",
+		"DASHLIGHT_ALPHA_0043=",
+		"DASHLIGHT_BRAVO_0042=",
+	}
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{"", "A C B "},
+		{"insertion", "A C B "},
+		{"name", "C B A "},
+		{"glyph", "A B C "},
+	}
+	for _, tt := range tests {
+		lights := make([]dashlight, 0)
+		parseEnviron(environ, &lights)
+		sortLights(&lights, tt.mode)
+		got := ""
+		for _, light := range lights {
+			got += light.Glyph + " "
+		}
+		if got != tt.expected {
+			t.Errorf("Expected order '%s' for mode '%s', got '%s'", tt.expected, tt.mode, got)
+		}
+	}
+}
+
+func TestLightsSortDisplay(t *testing.T) {
+	args.LightsSort = "name"
+	defer func() { args.LightsSort = "" }()
+
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_ZULU_005A=")
+	parseDashlightFromEnv(&lights, "DASHLIGHT_YANKEE_0059=")
+
+	args.ClearMode = true
+	defer func() { args.ClearMode = false }()
+	display(&b, &lights, nil)
+	expectStr := "unset DASHLIGHT_YANKEE_0059\nunset DASHLIGHT_ZULU_005A\n"
+	if b.String() != expectStr {
+		t.Errorf("Expected sorted clear codes '%s', got '%s'", expectStr, b.String())
+	}
+}
+
+func TestNoNewlineDisplay(t *testing.T) {
+	t.Setenv("DASHLIGHTS_NO_NEWLINE", "")
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_NN_0021=")
+	var b bytes.Buffer
+
+	display(&b, &lights, nil)
+	if !strings.HasSuffix(b.String(), "\n") {
+		t.Errorf("Expected trailing newline by default, got: %q", b.String())
+	}
+
+	args.NoNewline = true
+	b.Reset()
+	display(&b, &lights, nil)
+	args.NoNewline = false
+	if strings.HasSuffix(b.String(), "\n") {
+		t.Errorf("Expected no trailing newline with --no-newline, got: %q", b.String())
+	}
+
+	t.Setenv("DASHLIGHTS_NO_NEWLINE", "1")
+	b.Reset()
+	display(&b, &lights, nil)
+	if strings.HasSuffix(b.String(), "\n") {
+		t.Errorf("Expected no trailing newline with DASHLIGHTS_NO_NEWLINE, got: %q", b.String())
+	}
+
+	b.Reset()
+	empty := make([]dashlight, 0)
+	display(&b, &empty, nil)
+	if b.String() != "" {
+		t.Errorf("Expected no output without dashlights, got: %q", b.String())
+	}
+}
+
+func TestParseSettingFromEnv(t *testing.T) {
+	setting, name, value, ok := parseSettingFromEnv("DASHLIGHTS_PRIO_DEPLOY=-2")
+	if !ok || setting != "PRIO" || name != "DEPLOY" || value != "-2" {
+		t.Errorf("Expected PRIO setting for DEPLOY of -2, got '%s', '%s', '%s', %v", setting, name, value, ok)
+	}
+	setting, name, value, ok = parseSettingFromEnv("DASHLIGHTS_TTL_DEPLOY=1500000000")
+	if !ok || setting != "TTL" || name != "DEPLOY" || value != "1500000000" {
+		t.Errorf("Expected TTL setting for DEPLOY of 1500000000, got '%s', '%s', '%s', %v", setting, name, value, ok)
+	}
+	if _, _, _, ok := parseSettingFromEnv("DASHLIGHT_DEPLOY_0021=3"); ok {
+		t.Error("Expected ordinary dashvar not to be parsed as a setting")
+	}
+	if _, _, _, ok := parseSettingFromEnv("DASHLIGHTS_PRIO=3"); ok {
+		t.Error("Expected setting without a light name to be rejected")
+	}
+	if _, _, _, ok := parseSettingFromEnv("DASHLIGHT_TTL_DEPLOY=1500000000"); ok {
+		t.Error("Expected DASHLIGHT_ var not to be parsed as a setting")
+	}
+	// setting vars never render as lights, even when the name is valid hex...
+	lights := make([]dashlight, 0)
+	parseEnviron([]string{"DASHLIGHTS_PRIO_CAFE=1", "DASHLIGHTS_TTL_CAFE=1"}, &lights)
+	if 0 != len(lights) {
+		t.Error("Expected length of 0, got ", len(lights))
+	}
+	// ...and lights named like a setting still render
+	parseEnviron([]string{"DASHLIGHT_TTL_23F3_FGRED=", "DASHLIGHT_PRIO_0021=", "DASHLIGHT_CMD_0022="}, &lights)
+	if 3 != len(lights) || lights[0].Name != "TTL" || lights[0].Glyph != "\u23f3" {
+		t.Errorf("Expected lights named TTL, PRIO and CMD, got %+v", lights)
+	}
+}
+
+func TestPriorityOrdering(t *testing.T) {
+	environ := []string{
+		"DASHLIGHT_CHARLIE_0041=",
+		"DASHLIGHT_ALPHA_0043=",
+		"DASHLIGHT_BRAVO_0042=",
+		"DASHLIGHT_DELTA_0040=",
+		"DASHLIGHTS_PRIO_BRAVO=-1",
+		"DASHLIGHTS_PRIO_CHARLIE=5",
+		"DASHLIGHTS_PRIO_ALPHA=first",
+	}
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{"", "B C @ A "},
+		{"name", "B C @ A "},
+		{"glyph", "B @ C A "},
+	}
+	for _, tt := range tests {
+		lights := make([]dashlight, 0)
+		parseEnviron(environ, &lights)
+		sortLights(&lights, tt.mode)
+		got := ""
+		for _, light := range lights {
+			got += light.Glyph + " "
+		}
+		if got != tt.expected {
+			t.Errorf("Expected order '%s' for mode '%s', got '%s'", tt.expected, tt.mode, got)
+		}
+	}
+}
+
+func TestParseEnvironTTL(t *testing.T) {
+	environ := []string{
+		"DASHLIGHT_EXPIRED_0041=",
+		"DASHLIGHT_FUTURE_0042=",
+		"DASHLIGHT_FOREVER_0043=",
+		"DASHLIGHT_INVALID_0044=",
+		"DASHLIGHTS_TTL_EXPIRED=1000",
+		"DASHLIGHTS_TTL_FUTURE=4000",
+		"DASHLIGHTS_TTL_INVALID=tomorrow",
+	}
+	lights := make([]dashlight, 0)
+	parseEnviron(environ, &lights)
+	if lights[0].ExpiresAt == nil || !lights[0].ExpiresAt.Equal(time.Unix(1000, 0)) {
+		t.Error("Expected EXPIRED to expire at 1000, got ", lights[0].ExpiresAt)
+	}
+	if lights[2].ExpiresAt != nil || lights[3].ExpiresAt != nil {
+		t.Error("Expected no expiry for lights without a valid TTL")
+	}
+
+	active := activeLights(lights, time.Unix(2000, 0))
+	got := ""
+	for _, light := range active {
+		got += light.Name + " "
+	}
+	if got != "FUTURE FOREVER INVALID " {
+		t.Errorf("Expected expired light to be suppressed, got '%s'", got)
+	}
+}
+
+func TestExpiredDisplay(t *testing.T) {
+	past := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	environ := []string{
+		"DASHLIGHT_STALE_0021=stale diagnostic",
+		"DASHLIGHTS_TTL_STALE=" + past,
+	}
+	lights := make([]dashlight, 0)
+	parseEnviron(environ, &lights)
+
+	args.ObdMode = true
+	var b bytes.Buffer
+	display(&b, &lights, nil)
+	args.ObdMode = false
+	if strings.Contains(b.String(), "STALE") || strings.Contains(b.String(), "!") {
+		t.Errorf("Expected expired light to be suppressed, found: %s", b.String())
+	}
+
+	args.ClearMode = true
+	defer func() { args.ClearMode = false }()
+	b.Reset()
+	display(&b, &lights, nil)
+	expectStr := "unset DASHLIGHT_STALE_0021"
+	if !strings.Contains(b.String(), expectStr) {
+		t.Errorf("Clear mode should include expired lights: '%s', found: %s", expectStr, b.String())
+	}
+}
+
+func TestClearModeSettings(t *testing.T) {
+	environ := []string{
+		"DASHLIGHT_DEPLOY_1F680=deploying",
+		"DASHLIGHTS_TTL_DEPLOY=1000",
+		"DASHLIGHTS_PRIO_DEPLOY=1",
+		"DASHLIGHTS_CMD_DEPLOY=echo deploying",
+		"DASHLIGHTS_TTL_GONE=1000",
+		"DASHLIGHTS_NO_NEWLINE=1",
+	}
+	lights := make([]dashlight, 0)
+	settingVars := parseEnviron(environ, &lights)
+	args.ClearMode = true
+	defer func() { args.ClearMode = false }()
+
+	var b bytes.Buffer
+	display(&b, &lights, settingVars)
+	expectStr := "unset DASHLIGHT_DEPLOY_1F680\n" +
+		"unset DASHLIGHTS_TTL_DEPLOY\n" +
+		"unset DASHLIGHTS_PRIO_DEPLOY\n" +
+		"unset DASHLIGHTS_CMD_DEPLOY\n" +
+		"unset DASHLIGHTS_TTL_GONE\n"
+	if b.String() != expectStr {
+		t.Errorf("Expected clear codes for lights and setting vars:\n%s\ngot:\n%s", expectStr, b.String())
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// outputFormat resolves --output, mapping the deprecated --json flag onto it.
+func outputFormat() string {
+	if args.Output != "" {
+		return args.Output
+	}
+	if args.JSONMode {
+		return "json"
+	}
+	return "text"
+}
+
+// displayFormat renders lights in the given format, which validateArgs has
+// limited to those handled here; anything else renders as text. Only text
+// uses --obd to add diagnostics: the machine-readable formats always carry
+// every field they have, so consumers see a stable schema.
+func displayFormat(w io.Writer, lights *[]dashlight, format string) {
+	switch format {
+	case "json":
+		displayJSON(w, lights)
+	case "tsv":
+		displayTSV(w, lights)
+	case "sarif":
+		displaySARIF(w, lights)
+	case "prometheus":
+		displayPrometheus(w, lights)
+	default:
+		displayDashlights(w, lights)
+		if args.ObdMode {
+			displayDiagnostics(w, lights)
+		}
+	}
+}
+
+// showsDiagnostics reports whether the given format prints light
+// diagnostics, and so whether DASHLIGHTS_CMD_ commands are worth running.
+func showsDiagnostics(format string) bool {
+	switch format {
+	case "json", "tsv", "sarif":
+		return true
+	case "prometheus":
+		return false
+	default:
+		return args.ObdMode
+	}
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// displayTSV prints one light per line as name, glyph, diagnostic and unset
+// code, separated by tabs.
+func displayTSV(w io.Writer, lights *[]dashlight) {
+	for _, light := range *lights {
+		fields := []string{light.Name, light.Glyph, light.Diagnostic, light.UnsetString}
+		for i, field := range fields {
+			fields[i] = tsvEscaper.Replace(field)
+		}
+		flexPrintln(w, strings.Join(fields, "\t"))
+	}
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// displayPrometheus prints lights in the Prometheus text exposition format.
+func displayPrometheus(w io.Writer, lights *[]dashlight) {
+	flexPrintln(w, "# HELP dashlights_lights Number of dashlights currently set.")
+	flexPrintln(w, "# TYPE dashlights_lights gauge")
+	flexPrintf(w, "dashlights_lights %d\n", len(*lights))
+	flexPrintln(w, "# HELP dashlights_light A dashlight that is currently set.")
+	flexPrintln(w, "# TYPE dashlights_light gauge")
+	for _, light := range *lights {
+		flexPrintf(w, "dashlights_light{name=\"%s\",glyph=\"%s\"} 1\n",
+			promLabelEscaper.Replace(light.Name), promLabelEscaper.Replace(light.Glyph))
+	}
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID  string       `json:"ruleId"`
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// displaySARIF reports each light as a note-level SARIF result.
+func displaySARIF(w io.Writer, lights *[]dashlight) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "dashlights",
+			InformationURI: "https://github.com/erichs/dashlights",
+		}},
+		Results: make([]sarifResult, 0, len(*lights)),
+	}
+	for _, light := range *lights {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  light.Name,
+			Level:   "note",
+			Message: sarifMessage{Text: light.Glyph + " " + light.Diagnostic},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestOutputFormat(t *testing.T) {
+	if outputFormat() != "text" {
+		t.Error("Expected default output format 'text', got ", outputFormat())
+	}
+	args.JSONMode = true
+	defer func() { args.JSONMode = false }()
+	if outputFormat() != "json" {
+		t.Error("Expected --json to map onto 'json', got ", outputFormat())
+	}
+	args.Output = "tsv"
+	defer func() { args.Output = "" }()
+	if outputFormat() != "tsv" {
+		t.Error("Expected --output to take precedence over --json, got ", outputFormat())
+	}
+}
+
+func TestDisplayTSV(t *testing.T) {
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_TSV_0021=tab\there")
+	displayTSV(&b, &lights)
+	expectStr := "TSV\t!\ttab\\there\tunset DASHLIGHT_TSV_0021\n"
+	if b.String() != expectStr {
+		t.Errorf("Expected %q, got %q", expectStr, b.String())
+	}
+}
+
+func TestDisplayPrometheus(t *testing.T) {
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_PROM_0021=")
+	displayPrometheus(&b, &lights)
+	for _, expectStr := range []string{
+		"# TYPE dashlights_lights gauge\n",
+		"dashlights_lights 1\n",
+		"dashlights_light{name=\"PROM\",glyph=\"!\"} 1\n",
+	} {
+		if !strings.Contains(b.String(), expectStr) {
+			t.Errorf("Expected to see %q in:\n%s", expectStr, b.String())
+		}
+	}
+}
+
+func TestDisplaySARIF(t *testing.T) {
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_SARIF_0021=sarif diagnostic")
+	displaySARIF(&b, &lights)
+
+	var log sarifLog
+	if err := json.Unmarshal(b.Bytes(), &log); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v in:\n%s", err, b.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Expected a single SARIF 2.1.0 run, got:\n%s", b.String())
+	}
+	results := log.Runs[0].Results
+	if len(results) != 1 || results[0].RuleID != "SARIF" {
+		t.Fatalf("Expected one result for rule 'SARIF', got %+v", results)
+	}
+	if results[0].Message.Text != "! sarif diagnostic" {
+		t.Error("Expected glyph and diagnostic in message, got ", results[0].Message.Text)
+	}
+}
+
+func TestOutputModeDisplay(t *testing.T) {
+	t.Setenv("DASHLIGHTS_FORCE_COLOR", "")
+	t.Setenv("DASHLIGHTS_NO_NEWLINE", "")
+	t.Setenv("NO_COLOR", "1")
+	defer func() { args.Output = "" }()
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_OM_0021=")
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"text", "!  \n"},
+		{"json", `"schema": 1`},
+		{"tsv", "OM\t!\t"},
+		{"sarif", `"version": "2.1.0"`},
+		{"prometheus", "dashlights_lights 1"},
+	}
+	for _, tt := range tests {
+		args.Output = tt.format
+		var b bytes.Buffer
+		display(&b, &lights, nil)
+		if !strings.Contains(b.String(), tt.expected) {
+			t.Errorf("Expected --output %s to contain %q, found: %q", tt.format, tt.expected, b.String())
+		}
+	}
+}
+
+func TestOutputFormatsIgnoreObdMode(t *testing.T) {
+	defer func() { args.ObdMode = false }()
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_OBD_0021=obd diagnostic")
+
+	for _, format := range []string{"json", "tsv", "sarif", "prometheus"} {
+		var plain, obd bytes.Buffer
+		args.ObdMode = false
+		displayFormat(&plain, &lights, format)
+		args.ObdMode = true
+		displayFormat(&obd, &lights, format)
+		if plain.String() != obd.String() {
+			t.Errorf("Expected --output %s to be the same with --obd, got:\n%s\nand:\n%s", format, plain.String(), obd.String())
+		}
+		if format != "prometheus" && !strings.Contains(plain.String(), "obd diagnostic") {
+			t.Errorf("Expected --output %s to include the diagnostic without --obd, got:\n%s", format, plain.String())
+		}
+	}
+}
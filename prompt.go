@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// promptShell returns the shell to escape prompt output for, preferring
+// --shell over $SHELL.
+func promptShell() string {
+	if args.Shell != "" {
+		return args.Shell
+	}
+	return os.Getenv("SHELL")
+}
+
+// promptEscape wraps each ANSI color sequence in s with the non-printing
+// markers of the given shell, so prompt width calculations stay correct.
+// Shells without such markers get s back unchanged.
+func promptEscape(s, shell string) string {
+	var open, close string
+	switch filepath.Base(shell) {
+	case "bash":
+		// readline's raw ignore markers: bash decodes \[ and \] before
+		// running command substitution, so those would print literally.
+		open, close = "\001", "\002"
+	case "zsh":
+		open, close = "%{", "%}"
+	default:
+		return s
+	}
+	return ansiEscape.ReplaceAllStringFunc(s, func(seq string) string {
+		return open + seq + close
+	})
+}
+
+// displayPrompt renders dashlights for embedding in a shell prompt. Output is
+// usually captured by command substitution, so color is forced on unless
+// it has been disabled.
+func displayPrompt(w io.Writer, lights *[]dashlight, shell string) {
+	noColor := colorDisabled()
+	for _, light := range *lights {
+		if noColor {
+			light.Color.DisableColor()
+		} else {
+			light.Color.EnableColor()
+		}
+	}
+	var b bytes.Buffer
+	displayDashlights(&b, lights)
+	flexPrintf(w, "%s", promptEscape(b.String(), shell))
+}
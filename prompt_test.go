@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPromptEscape(t *testing.T) {
+	colored := "\x1b[31m!\x1b[0m "
+	tests := []struct {
+		shell    string
+		expected string
+	}{
+		{"/bin/bash", "\x01\x1b[31m\x02!\x01\x1b[0m\x02 "},
+		{"/usr/local/bin/zsh", "%{\x1b[31m%}!%{\x1b[0m%} "},
+		{"fish", colored},
+		{"", colored},
+	}
+	for _, tt := range tests {
+		if got := promptEscape(colored, tt.shell); got != tt.expected {
+			t.Errorf("Expected %q for shell '%s', got %q", tt.expected, tt.shell, got)
+		}
+	}
+}
+
+func TestPromptShell(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+
+	if promptShell() != "/bin/zsh" {
+		t.Error("Expected $SHELL to be used by default, got ", promptShell())
+	}
+	args.Shell = "bash"
+	defer func() { args.Shell = "" }()
+	if promptShell() != "bash" {
+		t.Error("Expected --shell to override $SHELL, got ", promptShell())
+	}
+}
+
+func TestPromptModeDisplay(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	args.PromptMode = true
+	args.Shell = "bash"
+	defer func() {
+		args.PromptMode = false
+		args.Shell = ""
+	}()
+
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_PM_0021_FGRED=")
+
+	display(&b, &lights, nil)
+	expectStr := "\x01\x1b[31m\x02!"
+	if !strings.Contains(b.String(), expectStr) {
+		t.Errorf("Expected to see %q in: %q", expectStr, b.String())
+	}
+	if strings.Count(b.String(), "\x1b[") != strings.Count(b.String(), "\x01\x1b[") {
+		t.Errorf("Expected every escape sequence to be wrapped, got: %q", b.String())
+	}
+}
+
+func TestPromptModeNoColor(t *testing.T) {
+	t.Setenv("DASHLIGHTS_FORCE_COLOR", "")
+	t.Setenv("NO_COLOR", "1")
+
+	var b bytes.Buffer
+	lights := make([]dashlight, 0)
+	parseDashlightFromEnv(&lights, "DASHLIGHT_PM_0021_FGRED=")
+
+	displayPrompt(&b, &lights, "zsh")
+	if strings.Contains(b.String(), "\x1b[") || strings.Contains(b.String(), "%{") {
+		t.Errorf("Expected no escapes with NO_COLOR set, got: %q", b.String())
+	}
+}